@@ -1,13 +1,17 @@
 package main
 
 import (
-	"bytes"
+	"encoding/json"
 	"fmt"
-	"github.com/spf13/cobra"
 	"io/ioutil"
-	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/maxpoletaev/hosts/pkg/hosts"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 // DebugFlag enables output to console instead of file.
@@ -16,121 +20,110 @@ var DebugFlag bool
 // HostsFile is a path to the hosts file.
 var HostsFile string
 
-// Host describes the row in hosts file.
-type Host struct {
-	IP        string
-	Hostnames []string
-}
+// BackupFlag enables writing a ".bak" copy of the hosts file before
+// overwriting it.
+var BackupFlag bool
 
-// ReadHosts parses the /etc/hosts file.
-func ReadHosts(hosts *[]interface{}) {
-	contents, err := ioutil.ReadFile(HostsFile)
-	if err != nil {
-		log.Fatal(err)
-	}
+// DryRunFlag makes fmt/fix print the proposed file instead of writing it.
+var DryRunFlag bool
 
-	rows := strings.Split(string(contents), "\n")
+// OnConflictFlag selects how `hosts fix` resolves a hostname that maps to
+// more than one IP: "first", "last", or "error".
+var OnConflictFlag string
 
-	for _, row := range rows {
-		row = strings.TrimSpace(row)
+// FormatFlag selects the rendering format: "unix" or "windows".
+var FormatFlag string
 
-		// skip comments and empty lines
-		if strings.HasPrefix(row, "#") || row == "" {
-			*hosts = append(*hosts, row)
-			continue
-		}
+// AllFlag makes `hosts list` also show disabled entries, with a status
+// column.
+var AllFlag bool
 
-		// skip invalid records
-		fields := strings.Fields(row)
-		if len(fields) < 2 {
-			*hosts = append(*hosts, row)
-			continue
-		}
+// OutputFlag selects how `hosts list` renders its results: "text", "json",
+// or "yaml".
+var OutputFlag string
 
-		ip := fields[0]
-		hostnames := fields[1:]
-		host := Host{ip, hostnames}
-		*hosts = append(*hosts, host)
-	}
+// listEntry is the structured form of a Host row printed by `hosts list
+// --output=json|yaml`.
+type listEntry struct {
+	IP        string   `json:"ip" yaml:"ip"`
+	Hostnames []string `json:"hostnames" yaml:"hostnames"`
+	Enabled   *bool    `json:"enabled,omitempty" yaml:"enabled,omitempty"`
 }
 
-// WriteHosts updates the /etc/hosts file.
-func WriteHosts(hosts []interface{}) {
-	renderedHosts := RenderHosts(hosts)
+// ManifestFlag is the path to the manifest passed to `hosts apply`. It
+// doesn't reuse the global -f/--file shorthand, which already means the
+// hosts file path.
+var ManifestFlag string
 
-	if DebugFlag {
-		fmt.Print(renderedHosts)
-		return
-	}
+// PruneFlag makes `hosts apply` remove managed entries absent from the
+// manifest.
+var PruneFlag bool
 
-	contents := []byte(renderedHosts)
-	err := ioutil.WriteFile(HostsFile, contents, 0644)
-	if err != nil {
-		log.Fatal(err)
-	}
-}
+// ManagedByFlag tags the entries `hosts apply` writes, so a later
+// --prune only ever touches entries it manages.
+var ManagedByFlag string
 
-// RenderHosts transforms code representation of the hosts file to string.
-func RenderHosts(hosts []interface{}) string {
-	var buf bytes.Buffer
+// DiffFlag makes `hosts apply` print the proposed changes instead of
+// applying them.
+var DiffFlag bool
 
-	for _, host := range hosts {
-		switch v := host.(type) {
+// parseManifest decodes a `hosts apply` manifest, trying JSON for a
+// ".json" path and YAML otherwise.
+func parseManifest(path string) ([]hosts.ManifestEntry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
 
-		case string:
-			buf.WriteString(v)
+	var entries []hosts.ManifestEntry
 
-		case Host:
-			hostnames := strings.Join(v.Hostnames, " ")
-			row := v.IP + "\t" + hostnames + "\n"
-			buf.WriteString(row)
-		}
+	if filepath.Ext(path) == ".json" {
+		err = json.Unmarshal(data, &entries)
+	} else {
+		err = yaml.Unmarshal(data, &entries)
 	}
 
-	return buf.String()
+	return entries, err
 }
 
-// RenderHostsWithoutComments transforms code representation of the hosts
-// file to string, but ignores empty and commented (that starts with #) strings.
-func RenderHostsWithoutComments(hosts []interface{}) string {
-	var buf bytes.Buffer
-
-	for _, host := range hosts {
-		if v, ok := host.(Host); ok {
-			hostnames := strings.Join(v.Hostnames, " ")
-			row := v.IP + "\t" + hostnames + "\n"
-			buf.WriteString(row)
-		}
+// parseFormat converts FormatFlag into a hosts.Format, exiting the process
+// on an unrecognized value.
+func parseFormat(s string) hosts.Format {
+	switch s {
+	case "unix":
+		return hosts.FormatUnix
+	case "windows":
+		return hosts.FormatWindows
+	default:
+		fmt.Printf("invalid format %q: must be \"unix\" or \"windows\"\n", s)
+		os.Exit(1)
+		return hosts.FormatUnix
 	}
-
-	return buf.String()
 }
 
-func uniqueStrings(s []string) []string {
-	seen := make(map[string]struct{}, len(s))
-	j := 0
-	for _, v := range s {
-		if _, ok := seen[v]; ok {
-			continue
-		}
-		seen[v] = struct{}{}
-		s[j] = v
-		j++
+// loadHostfile reads HostsFile from disk, exiting the process on error.
+func loadHostfile() *hosts.Hostfile {
+	hf := hosts.NewHostfile(HostsFile)
+	hf.SetFormat(parseFormat(FormatFlag))
+	if err := hf.Load(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
-	return s[:j]
+	return hf
 }
 
-func removeHostname(hostnames []string, remove string) []string {
-	removeIndex := -1
-	for i, hostname := range hostnames {
-		if hostname == remove {
-			removeIndex = i
-		}
+// saveHostfile writes hf back to disk, unless DebugFlag is set, in which
+// case it is printed to the console instead.
+func saveHostfile(hf *hosts.Hostfile) {
+	if DebugFlag {
+		fmt.Print(hf.Render())
+		return
 	}
-	if removeIndex != -1 {
-		hostnames = append(hostnames[:removeIndex], hostnames[removeIndex+1:]...)
+
+	if err := hf.Save(BackupFlag); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
-	return hostnames
 }
 
 var rootCmd = &cobra.Command{
@@ -138,130 +131,302 @@ var rootCmd = &cobra.Command{
 }
 
 var cmdAddHost = &cobra.Command{
-	Use:  "add ip hostname [hostname ...]",
+	Use:   "add ip hostname [hostname ...]",
 	Short: "Add host to the hosts file",
-	Args: cobra.MinimumNArgs(2),
+	Args:  cobra.MinimumNArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
-		var hosts []interface{}
-		ReadHosts(&hosts)
-
-		ip := args[0]
-		hostnames := args[1:]
-
-		updated := false
-		for i, host := range hosts {
-			if host, ok := host.(Host); ok {
-				if host.IP == ip {
-					hostnames = append(host.Hostnames, hostnames...)
-					host.Hostnames = uniqueStrings(hostnames)
-					hosts[i] = host
-					updated = true
-					break
-				}
-			}
+		hf := loadHostfile()
+		if err := hf.AddHost(args[0], args[1:]...); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
 		}
-
-		if !updated {
-			host := Host{ip, uniqueStrings(hostnames)}
-			hosts = append(hosts, host)
-		}
-
-		WriteHosts(hosts)
+		saveHostfile(hf)
 	},
 }
 
 var cmdResolve = &cobra.Command{
-	Use:  "resolve ip",
+	Use:   "resolve hostname",
 	Short: "Resolve hostname to IP address",
-	Args: cobra.ExactArgs(1),
+	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		var hosts []interface{}
-		ReadHosts(&hosts)
-
-		searchHostname := args[0]
+		hf := loadHostfile()
 
-		for _, host := range hosts {
-			if host, ok := host.(Host); ok {
-				for _, hostname := range host.Hostnames {
-					if hostname == searchHostname {
-						fmt.Println(host.IP)
-						return
-					}
-				}
-			}
+		matches := hf.ListAddressesByHost(args[0], true)
+		if len(matches) > 0 {
+			fmt.Println(matches[0][0])
 		}
 	},
 }
 
 var cmdList = &cobra.Command{
-	Use:  "list",
+	Use:   "list",
 	Short: "List all hosts",
-	Args: cobra.NoArgs,
+	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		var hosts []interface{}
-		ReadHosts(&hosts)
+		hf := loadHostfile()
 
-		renderedHosts := RenderHostsWithoutComments(hosts)
-		fmt.Print(renderedHosts)
+		var entries []listEntry
+		for _, host := range hf.Hosts() {
+			if !AllFlag && !host.Enabled {
+				continue
+			}
+
+			entry := listEntry{IP: host.IP, Hostnames: host.Hostnames}
+			if AllFlag {
+				enabled := host.Enabled
+				entry.Enabled = &enabled
+			}
+			entries = append(entries, entry)
+		}
+
+		switch OutputFlag {
+		case "json":
+			data, err := json.MarshalIndent(entries, "", "  ")
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			fmt.Println(string(data))
+
+		case "yaml":
+			data, err := yaml.Marshal(entries)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			fmt.Print(string(data))
+
+		case "text":
+			for _, entry := range entries {
+				if entry.Enabled != nil {
+					status := "enabled"
+					if !*entry.Enabled {
+						status = "disabled"
+					}
+					fmt.Printf("%s\t%s\t%s\n", status, entry.IP, strings.Join(entry.Hostnames, " "))
+					continue
+				}
+				fmt.Printf("%s\t%s\n", entry.IP, strings.Join(entry.Hostnames, " "))
+			}
+
+		default:
+			fmt.Printf("invalid output %q: must be \"text\", \"json\", or \"yaml\"\n", OutputFlag)
+			os.Exit(1)
+		}
 	},
 }
 
 var cmdRemoveIP = &cobra.Command{
-	Use:  "rmip ip",
+	Use:   "rmip ip",
 	Short: "Remove IP address from the hosts file.",
-	Args: cobra.ExactArgs(1),
+	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		var hosts []interface{}
-		ReadHosts(&hosts)
-
-		ip := args[0]
-		for i := len(hosts) - 1; i >= 0; i-- {
-			if host, ok := hosts[i].(Host); ok {
-				if host.IP == ip {
-					hosts = append(hosts[:i], hosts[i+1:])
-				}
-			}
-		}
-
-		WriteHosts(hosts)
+		hf := loadHostfile()
+		hf.RemoveIP(args[0])
+		saveHostfile(hf)
 	},
 }
 
 var cmdRemoveHost = &cobra.Command{
-	Use:  "rmhost hostname [hostname ...]",
+	Use:   "rmhost hostname [hostname ...]",
 	Short: "Remove hostname from the hosts file",
-	Args: cobra.MinimumNArgs(1),
+	Args:  cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		var hosts []interface{}
-		ReadHosts(&hosts)
-
-		hostnames := args
-		for i := len(hosts) - 1; i >= 0; i-- {
-			if host, ok := hosts[i].(Host); ok {
-				for _, hostname := range hostnames {
-					host.Hostnames = removeHostname(host.Hostnames, hostname)
-				}
-				if len(host.Hostnames) > 0 {
-					hosts[i] = host
-				} else {
-					hosts = append(hosts[:i], hosts[i+1:]...)
-				}
-			}
+		hf := loadHostfile()
+		hf.RemoveHost(args...)
+		saveHostfile(hf)
+	},
+}
+
+var cmdRestore = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore the hosts file from its .bak backup",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		hf := hosts.NewHostfile(HostsFile)
+		if err := hf.Restore(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var cmdFmt = &cobra.Command{
+	Use:   "fmt",
+	Short: "Rewrite the hosts file in canonical form",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		hf := loadHostfile()
+		hf.Format()
+		rendered := hf.RenderCanonical()
+
+		if DryRunFlag || DebugFlag {
+			fmt.Print(rendered)
+			return
+		}
+
+		if err := hf.SaveCanonical(BackupFlag); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var cmdFix = &cobra.Command{
+	Use:   "fix",
+	Short: "Detect and resolve hostnames that map to conflicting IPs",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		hf := loadHostfile()
+
+		conflicts, err := hf.ResolveConflicts(OnConflictFlag)
+		for _, c := range conflicts {
+			fmt.Printf("%s -> %s\n", c.Hostname, strings.Join(c.IPs, ", "))
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if DryRunFlag || DebugFlag {
+			fmt.Print(hf.Render())
+			return
+		}
+
+		if err := hf.Save(BackupFlag); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var cmdOn = &cobra.Command{
+	Use:   "on hostname",
+	Short: "Enable a disabled hosts file entry",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		hf := loadHostfile()
+		if !hf.SetEnabled(args[0], true) {
+			fmt.Printf("hostname not found: %s\n", args[0])
+			os.Exit(1)
 		}
+		saveHostfile(hf)
+	},
+}
 
-		WriteHosts(hosts)
+var cmdOff = &cobra.Command{
+	Use:   "off hostname",
+	Short: "Disable a hosts file entry without removing it",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		hf := loadHostfile()
+		if !hf.SetEnabled(args[0], false) {
+			fmt.Printf("hostname not found: %s\n", args[0])
+			os.Exit(1)
+		}
+		saveHostfile(hf)
+	},
+}
+
+var cmdInfo = &cobra.Command{
+	Use:   "info",
+	Short: "Show summary metrics about the hosts file",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		hf := loadHostfile()
+
+		info, err := hf.Info()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("path:      %s\n", info.Path)
+		fmt.Printf("modified:  %s\n", info.ModTime.Format(time.RFC3339))
+		fmt.Printf("entries:   %d\n", info.Entries)
+		fmt.Printf("hostnames: %d\n", info.Hostnames)
+		fmt.Printf("disabled:  %d\n", info.Disabled)
+		fmt.Printf("conflicts: %d\n", info.Conflicts)
+		fmt.Printf("ipv4:      %d\n", info.IPv4)
+		fmt.Printf("ipv6:      %d\n", info.IPv6)
+		fmt.Printf("loopback:  %d\n", info.Loopback)
+		fmt.Printf("private:   %d\n", info.Private)
+		fmt.Printf("public:    %d\n", info.Public)
+	},
+}
+
+var cmdApply = &cobra.Command{
+	Use:   "apply",
+	Short: "Reconcile the hosts file with a declarative manifest",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if ManifestFlag == "" {
+			fmt.Println("--manifest is required")
+			os.Exit(1)
+		}
+
+		entries, err := parseManifest(ManifestFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		hf := loadHostfile()
+		result := hf.Apply(entries, ManagedByFlag, PruneFlag)
+
+		for _, host := range result.Added {
+			fmt.Printf("+ %s %s\n", host.IP, strings.Join(host.Hostnames, " "))
+		}
+		for _, host := range result.Changed {
+			fmt.Printf("~ %s %s\n", host.IP, strings.Join(host.Hostnames, " "))
+		}
+		for _, host := range result.Removed {
+			fmt.Printf("- %s %s\n", host.IP, strings.Join(host.Hostnames, " "))
+		}
+
+		if DiffFlag || DryRunFlag || DebugFlag {
+			return
+		}
+
+		if err := hf.Save(BackupFlag); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 	},
 }
 
 func main() {
 	rootCmd.PersistentFlags().BoolVarP(&DebugFlag, "debug", "d", false, "print output to console instead of file")
 	rootCmd.PersistentFlags().StringVarP(&HostsFile, "file", "f", "/etc/hosts", "path to the hosts file")
+	rootCmd.PersistentFlags().BoolVar(&BackupFlag, "backup", false, "write a .bak copy of the hosts file before overwriting it")
+	rootCmd.PersistentFlags().StringVar(&FormatFlag, "format", "unix", "rendering format: unix|windows")
 
+	cmdList.Flags().BoolVar(&AllFlag, "all", false, "show both enabled and disabled entries, with a status column")
+	cmdList.Flags().StringVar(&OutputFlag, "output", "text", "output format: text|json|yaml")
 	rootCmd.AddCommand(cmdList)
+
 	rootCmd.AddCommand(cmdResolve)
 	rootCmd.AddCommand(cmdAddHost)
 	rootCmd.AddCommand(cmdRemoveIP)
 	rootCmd.AddCommand(cmdRemoveHost)
+	rootCmd.AddCommand(cmdRestore)
+	rootCmd.AddCommand(cmdOn)
+	rootCmd.AddCommand(cmdOff)
+	rootCmd.AddCommand(cmdInfo)
+
+	cmdFmt.Flags().BoolVarP(&DryRunFlag, "dry-run", "n", false, "print the proposed file instead of writing it")
+	rootCmd.AddCommand(cmdFmt)
+
+	cmdFix.Flags().BoolVarP(&DryRunFlag, "dry-run", "n", false, "print the proposed file instead of writing it")
+	cmdFix.Flags().StringVar(&OnConflictFlag, "on-conflict", "error", "how to resolve a hostname with conflicting IPs: first|last|error")
+	rootCmd.AddCommand(cmdFix)
+
+	cmdApply.Flags().StringVarP(&ManifestFlag, "manifest", "m", "", "path to the manifest file (YAML or JSON)")
+	cmdApply.Flags().BoolVar(&PruneFlag, "prune", false, "remove managed entries absent from the manifest")
+	cmdApply.Flags().StringVar(&ManagedByFlag, "managed-by", "hosts", "tag applied to entries this command manages")
+	cmdApply.Flags().BoolVar(&DiffFlag, "diff", false, "print the proposed changes instead of applying them")
+	cmdApply.Flags().BoolVarP(&DryRunFlag, "dry-run", "n", false, "print the proposed changes instead of applying them")
+	rootCmd.AddCommand(cmdApply)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
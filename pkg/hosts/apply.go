@@ -0,0 +1,130 @@
+package hosts
+
+import (
+	"sort"
+	"strings"
+)
+
+// ManifestEntry is one desired entry in a declarative apply manifest.
+type ManifestEntry struct {
+	IP        string   `json:"ip" yaml:"ip"`
+	Hostnames []string `json:"hostnames" yaml:"hostnames"`
+	Comment   string   `json:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+// ApplyResult summarizes what Apply changed.
+type ApplyResult struct {
+	Added   []Host
+	Changed []Host
+	Removed []Host
+}
+
+// managedByPrefix is the marker comment Apply writes above every entry it
+// manages, so a later Apply (and Apply's --prune) can tell its own entries
+// apart from ones the user added by hand.
+func managedByPrefix(tag string) string {
+	return "# managed-by:" + tag
+}
+
+// isManagedMarker reports whether marker is this tag's own managed-by
+// marker, optionally followed by a comment. A plain strings.HasPrefix
+// check would also match a different, longer tag that happens to start
+// with this one (e.g. "hosts" matching "# managed-by:hostsctl").
+func isManagedMarker(marker, prefix string) bool {
+	return marker == prefix || strings.HasPrefix(marker, prefix+" ")
+}
+
+// Apply reconciles the loaded rows with entries: existing managed rows for
+// an IP are updated in place if their hostnames changed, new ones are
+// appended with a managed-by marker comment, and, if prune is true, any
+// previously managed IP absent from entries is removed.
+func (h *Hostfile) Apply(entries []ManifestEntry, managedBy string, prune bool) ApplyResult {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	prefix := managedByPrefix(managedBy)
+
+	managedIdx := make(map[string]int)
+	for i := 1; i < len(h.rows); i++ {
+		host, ok := h.rows[i].(Host)
+		if !ok {
+			continue
+		}
+		if marker, ok := h.rows[i-1].(string); ok && isManagedMarker(marker, prefix) {
+			managedIdx[host.IP] = i
+		}
+	}
+
+	var result ApplyResult
+	seen := make(map[string]bool, len(entries))
+
+	for _, entry := range entries {
+		seen[entry.IP] = true
+		want := Host{IP: entry.IP, Hostnames: append([]string(nil), entry.Hostnames...), Enabled: true}
+
+		if idx, exists := managedIdx[entry.IP]; exists {
+			if !sameHostnames(h.rows[idx].(Host).Hostnames, want.Hostnames) {
+				h.rows[idx] = want
+				result.Changed = append(result.Changed, want)
+			}
+			continue
+		}
+
+		marker := prefix
+		if entry.Comment != "" {
+			marker += " " + entry.Comment
+		}
+
+		h.rows = append(h.rows, marker, want)
+		result.Added = append(result.Added, want)
+	}
+
+	if prune {
+		for ip, idx := range managedIdx {
+			if !seen[ip] {
+				result.Removed = append(result.Removed, h.rows[idx].(Host))
+			}
+		}
+		if len(result.Removed) > 0 {
+			h.rows = pruneManagedRows(h.rows, prefix, seen)
+		}
+	}
+
+	return result
+}
+
+// pruneManagedRows drops every "marker comment, Host" pair whose marker
+// has the given prefix and whose Host IP isn't in keep.
+func pruneManagedRows(rows []interface{}, prefix string, keep map[string]bool) []interface{} {
+	out := rows[:0]
+
+	for i := 0; i < len(rows); i++ {
+		if marker, ok := rows[i].(string); ok && isManagedMarker(marker, prefix) && i+1 < len(rows) {
+			if host, ok := rows[i+1].(Host); ok && !keep[host.IP] {
+				i++ // also drop the Host row that follows the marker
+				continue
+			}
+		}
+		out = append(out, rows[i])
+	}
+
+	return out
+}
+
+func sameHostnames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	a, b = append([]string(nil), a...), append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
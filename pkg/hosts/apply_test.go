@@ -0,0 +1,118 @@
+package hosts
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestApplyAddedEntrySurvivesReload(t *testing.T) {
+	hf := writeTempHostfile(t, "127.0.0.1 localhost\n")
+
+	entries := []ManifestEntry{{IP: "10.0.0.1", Hostnames: []string{"myapp"}}}
+	result := hf.Apply(entries, "hosts", false)
+
+	if len(result.Added) != 1 {
+		t.Fatalf("Apply result.Added = %v, want 1 entry", result.Added)
+	}
+
+	if err := hf.Save(false); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	reloaded := NewHostfile(hf.path)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("reload Load: %v", err)
+	}
+
+	if got := reloaded.ListHostsByIP("10.0.0.1"); len(got) != 1 || got[0] != "myapp" {
+		t.Fatalf("ListHostsByIP(10.0.0.1) after reload = %v, want [myapp]", got)
+	}
+}
+
+func TestApplyUpdatesExistingManagedEntry(t *testing.T) {
+	hf := writeTempHostfile(t, "")
+
+	hf.Apply([]ManifestEntry{{IP: "10.0.0.1", Hostnames: []string{"myapp"}}}, "hosts", false)
+	result := hf.Apply([]ManifestEntry{{IP: "10.0.0.1", Hostnames: []string{"myapp", "myapp2"}}}, "hosts", false)
+
+	if len(result.Changed) != 1 {
+		t.Fatalf("Apply result.Changed = %v, want 1 entry", result.Changed)
+	}
+	if got := hf.ListHostsByIP("10.0.0.1"); len(got) != 2 {
+		t.Fatalf("ListHostsByIP(10.0.0.1) = %v, want 2 hostnames", got)
+	}
+}
+
+func TestApplyPruneOnlyRemovesOwnTag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	contents := "# managed-by:hostsctl\n10.0.0.9\tunrelated\n"
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	hf := NewHostfile(path)
+	if err := hf.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	result := hf.Apply(nil, "hosts", true)
+
+	if len(result.Removed) != 0 {
+		t.Fatalf("Apply with --managed-by=hosts --prune removed %v, want none (owned by hostsctl)", result.Removed)
+	}
+	if got := hf.ListHostsByIP("10.0.0.9"); len(got) != 1 || got[0] != "unrelated" {
+		t.Fatalf("ListHostsByIP(10.0.0.9) = %v, want [unrelated] to survive prune", got)
+	}
+}
+
+func TestApplyPruneRemovesOwnManagedEntry(t *testing.T) {
+	hf := writeTempHostfile(t, "")
+	hf.Apply([]ManifestEntry{{IP: "10.0.0.1", Hostnames: []string{"myapp"}}}, "hosts", false)
+
+	result := hf.Apply(nil, "hosts", true)
+
+	if len(result.Removed) != 1 {
+		t.Fatalf("Apply prune with no entries = %v, want 1 removed", result.Removed)
+	}
+	if got := hf.ListHostsByIP("10.0.0.1"); got != nil {
+		t.Fatalf("ListHostsByIP(10.0.0.1) after prune = %v, want nil", got)
+	}
+}
+
+func TestManifestEntryJSONRoundTrip(t *testing.T) {
+	entry := ManifestEntry{IP: "10.0.0.1", Hostnames: []string{"myapp"}, Comment: "from test"}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	var got ManifestEntry
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	if got.IP != entry.IP || got.Comment != entry.Comment || !sameHostnames(got.Hostnames, entry.Hostnames) {
+		t.Fatalf("json round-trip = %+v, want %+v", got, entry)
+	}
+}
+
+func TestManifestEntryYAMLRoundTrip(t *testing.T) {
+	entry := ManifestEntry{IP: "10.0.0.1", Hostnames: []string{"myapp"}}
+
+	data, err := yaml.Marshal([]ManifestEntry{entry})
+	if err != nil {
+		t.Fatalf("yaml.Marshal: %v", err)
+	}
+
+	var got []ManifestEntry
+	if err := yaml.Unmarshal(data, &got); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	if len(got) != 1 || got[0].IP != entry.IP || !sameHostnames(got[0].Hostnames, entry.Hostnames) {
+		t.Fatalf("yaml round-trip = %+v, want [%+v]", got, entry)
+	}
+}
@@ -0,0 +1,218 @@
+package hosts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ErrConflict is returned by ResolveConflicts when the "error" policy is
+// in effect and one or more hostnames map to conflicting IPs.
+var ErrConflict = errors.New("hosts: conflicting entries")
+
+// Conflict describes a hostname that resolves to more than one IP address.
+type Conflict struct {
+	Hostname string
+	IPs      []string
+}
+
+// Format rewrites the loaded rows into canonical form: hostnames for a
+// given IP are merged into a single row, deduped and sorted, and
+// duplicate rows for the same IP are dropped. Comments and blank lines
+// keep their original positions.
+func (h *Hostfile) Format() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.rows = formatRows(h.rows)
+}
+
+// rowKey groups Host rows for merging. Enabled is part of the key so a
+// disabled entry never silently merges into (and re-enables alongside) an
+// enabled one for the same IP.
+type rowKey struct {
+	ip      string
+	enabled bool
+}
+
+func formatRows(rows []interface{}) []interface{} {
+	out := make([]interface{}, 0, len(rows))
+	index := make(map[rowKey]int, len(rows))
+
+	for _, row := range rows {
+		host, ok := row.(Host)
+		if !ok {
+			out = append(out, row)
+			continue
+		}
+
+		key := rowKey{ip: host.IP, enabled: host.Enabled}
+
+		if i, seen := index[key]; seen {
+			existing := out[i].(Host)
+			existing.Hostnames = uniqueStrings(append(existing.Hostnames, host.Hostnames...))
+			sort.Strings(existing.Hostnames)
+			out[i] = existing
+			continue
+		}
+
+		host.Hostnames = uniqueStrings(append([]string(nil), host.Hostnames...))
+		sort.Strings(host.Hostnames)
+		index[key] = len(out)
+		out = append(out, host)
+	}
+
+	return out
+}
+
+// RenderCanonical renders rows the same way RenderHosts does, except IP
+// columns are aligned so hostnames start at the same offset on every row.
+func RenderCanonical(rows []interface{}) string {
+	width := 0
+	for _, row := range rows {
+		if host, ok := row.(Host); ok && len(host.IP) > width {
+			width = len(host.IP)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	for _, row := range rows {
+		switch v := row.(type) {
+
+		case string:
+			buf.WriteString(v)
+			buf.WriteString("\n")
+
+		case Host:
+			prefix := ""
+			if !v.Enabled {
+				prefix = "# "
+			}
+			hostnames := strings.Join(v.Hostnames, " ")
+			fmt.Fprintf(&buf, "%s%-*s  %s\n", prefix, width, v.IP, hostnames)
+		}
+	}
+
+	return buf.String()
+}
+
+// RenderCanonical returns the loaded rows rendered in canonical form. Call
+// Format first to merge/dedupe/sort the underlying rows.
+func (h *Hostfile) RenderCanonical() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return RenderCanonical(h.rows)
+}
+
+// SaveCanonical writes the loaded rows to the hosts file path in canonical
+// form (see RenderCanonical), atomically and with an optional backup.
+func (h *Hostfile) SaveCanonical(backup bool) error {
+	h.mu.RLock()
+	rendered := RenderCanonical(h.rows)
+	h.mu.RUnlock()
+
+	if backup {
+		if err := copyFile(h.path, h.path+".bak"); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return writeFileAtomic(h.path, []byte(rendered))
+}
+
+// Conflicts reports every hostname that resolves to more than one IP
+// address, in the order the hostname was first seen.
+func (h *Hostfile) Conflicts() []Conflict {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return findConflicts(h.rows)
+}
+
+func findConflicts(rows []interface{}) []Conflict {
+	ips := make(map[string][]string)
+	var order []string
+
+	for _, row := range rows {
+		host, ok := row.(Host)
+		if !ok {
+			continue
+		}
+
+		for _, name := range host.Hostnames {
+			if _, seen := ips[name]; !seen {
+				order = append(order, name)
+			}
+			ips[name] = uniqueStrings(append(ips[name], host.IP))
+		}
+	}
+
+	var conflicts []Conflict
+	for _, name := range order {
+		if len(ips[name]) > 1 {
+			conflicts = append(conflicts, Conflict{Hostname: name, IPs: ips[name]})
+		}
+	}
+
+	return conflicts
+}
+
+// ResolveConflicts detects hostnames that map to more than one IP and
+// resolves them according to policy:
+//
+//   - "first": keep the hostname on the IP it first appeared on
+//   - "last":  keep the hostname on the IP it last appeared on
+//   - "error": resolve nothing; return ErrConflict if any conflict exists
+//
+// It returns every conflict found, whether or not it could be resolved.
+func (h *Hostfile) ResolveConflicts(policy string) ([]Conflict, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	conflicts := findConflicts(h.rows)
+	if len(conflicts) == 0 {
+		return nil, nil
+	}
+
+	if policy == "error" {
+		return conflicts, ErrConflict
+	}
+
+	for _, c := range conflicts {
+		var keep string
+		switch policy {
+		case "first":
+			keep = c.IPs[0]
+		case "last":
+			keep = c.IPs[len(c.IPs)-1]
+		default:
+			return conflicts, fmt.Errorf("hosts: unknown conflict policy %q", policy)
+		}
+
+		for i, row := range h.rows {
+			host, ok := row.(Host)
+			if !ok || host.IP == keep {
+				continue
+			}
+			host.Hostnames = removeHostname(host.Hostnames, c.Hostname)
+			h.rows[i] = host
+		}
+	}
+
+	h.rows = dropEmptyHosts(h.rows)
+
+	return conflicts, nil
+}
+
+func dropEmptyHosts(rows []interface{}) []interface{} {
+	out := rows[:0]
+	for _, row := range rows {
+		if host, ok := row.(Host); ok && len(host.Hostnames) == 0 {
+			continue
+		}
+		out = append(out, row)
+	}
+	return out
+}
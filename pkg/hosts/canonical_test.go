@@ -0,0 +1,77 @@
+package hosts
+
+import "testing"
+
+func TestResolveConflictsFirst(t *testing.T) {
+	hf := writeTempHostfile(t, "10.0.0.1 myapp\n10.0.0.2 myapp\n")
+
+	conflicts, err := hf.ResolveConflicts("first")
+	if err != nil {
+		t.Fatalf("ResolveConflicts: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Hostname != "myapp" {
+		t.Fatalf("ResolveConflicts conflicts = %v, want one for myapp", conflicts)
+	}
+
+	if got := hf.ListHostsByIP("10.0.0.1"); len(got) != 1 || got[0] != "myapp" {
+		t.Fatalf("ListHostsByIP(10.0.0.1) after \"first\" resolution = %v, want [myapp]", got)
+	}
+	if got := hf.ListHostsByIP("10.0.0.2"); got != nil {
+		t.Fatalf("ListHostsByIP(10.0.0.2) after \"first\" resolution = %v, want nil", got)
+	}
+}
+
+func TestResolveConflictsLast(t *testing.T) {
+	hf := writeTempHostfile(t, "10.0.0.1 myapp\n10.0.0.2 myapp\n")
+
+	if _, err := hf.ResolveConflicts("last"); err != nil {
+		t.Fatalf("ResolveConflicts: %v", err)
+	}
+
+	if got := hf.ListHostsByIP("10.0.0.2"); len(got) != 1 || got[0] != "myapp" {
+		t.Fatalf("ListHostsByIP(10.0.0.2) after \"last\" resolution = %v, want [myapp]", got)
+	}
+	if got := hf.ListHostsByIP("10.0.0.1"); got != nil {
+		t.Fatalf("ListHostsByIP(10.0.0.1) after \"last\" resolution = %v, want nil", got)
+	}
+}
+
+func TestResolveConflictsError(t *testing.T) {
+	hf := writeTempHostfile(t, "10.0.0.1 myapp\n10.0.0.2 myapp\n")
+
+	conflicts, err := hf.ResolveConflicts("error")
+	if err != ErrConflict {
+		t.Fatalf("ResolveConflicts(\"error\") err = %v, want ErrConflict", err)
+	}
+	if len(conflicts) != 1 {
+		t.Fatalf("ResolveConflicts(\"error\") conflicts = %v, want one", conflicts)
+	}
+
+	// the "error" policy reports but never mutates
+	if got := hf.ListHostsByIP("10.0.0.1"); len(got) != 1 || got[0] != "myapp" {
+		t.Fatalf("ListHostsByIP(10.0.0.1) after \"error\" policy = %v, want unchanged [myapp]", got)
+	}
+}
+
+func TestResolveConflictsNoConflicts(t *testing.T) {
+	hf := writeTempHostfile(t, "10.0.0.1 myapp\n")
+
+	conflicts, err := hf.ResolveConflicts("first")
+	if err != nil {
+		t.Fatalf("ResolveConflicts: %v", err)
+	}
+	if conflicts != nil {
+		t.Fatalf("ResolveConflicts with no conflicts = %v, want nil", conflicts)
+	}
+}
+
+func TestFormatMergesAndSortsHostnames(t *testing.T) {
+	hf := writeTempHostfile(t, "10.0.0.1 zeta\n10.0.0.1 alpha\n")
+	hf.Format()
+
+	got := hf.ListHostsByIP("10.0.0.1")
+	want := []string{"alpha", "zeta"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("ListHostsByIP(10.0.0.1) after Format = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,417 @@
+// Package hosts provides programmatic read/write access to a hosts file
+// (e.g. /etc/hosts), so that tools other than the hosts CLI can inspect or
+// mutate it directly.
+package hosts
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Host describes a single row in a hosts file.
+type Host struct {
+	IP        string
+	Hostnames []string
+
+	// Enabled is false for an entry that has been commented out with
+	// `hosts off` rather than removed outright.
+	Enabled bool
+}
+
+// Format selects how a hosts file's rows are rendered back to text.
+type Format int
+
+const (
+	// FormatUnix groups every hostname for an IP onto a single line, the
+	// format read by glibc and most other Unix resolvers.
+	FormatUnix Format = iota
+
+	// FormatWindows emits one "IP\thostname" pair per line, the layout
+	// Windows' hosts parser handles most reliably.
+	FormatWindows
+)
+
+// Hostfile is an in-memory representation of a hosts file. It is safe for
+// concurrent use by multiple goroutines.
+type Hostfile struct {
+	mu     sync.RWMutex
+	path   string
+	rows   []interface{}
+	format Format
+}
+
+// NewHostfile returns an empty Hostfile backed by the file at path. Call
+// Load to populate it from disk.
+func NewHostfile(path string) *Hostfile {
+	return &Hostfile{path: path}
+}
+
+// Load reads and parses the hosts file from disk, replacing any
+// previously loaded contents.
+func (h *Hostfile) Load() error {
+	contents, err := ioutil.ReadFile(h.path)
+	if err != nil {
+		return err
+	}
+
+	rows := parseRows(contents)
+
+	h.mu.Lock()
+	h.rows = rows
+	h.mu.Unlock()
+
+	return nil
+}
+
+// parseRows splits the raw contents of a hosts file into rows, preserving
+// comments and blank lines so the file can be rendered back out unchanged.
+func parseRows(contents []byte) []interface{} {
+	var rows []interface{}
+	lines := strings.Split(string(contents), "\n")
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+
+		if line == "" {
+			rows = append(rows, line)
+			continue
+		}
+
+		// a commented-out hosts row (e.g. "# 127.0.0.1 foo") is a disabled
+		// entry, not opaque comment text
+		if strings.HasPrefix(line, "#") {
+			if host, ok := parseDisabledHost(line); ok {
+				rows = append(rows, host)
+				continue
+			}
+			rows = append(rows, line)
+			continue
+		}
+
+		// skip invalid records
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			rows = append(rows, line)
+			continue
+		}
+
+		rows = append(rows, Host{IP: fields[0], Hostnames: fields[1:], Enabled: true})
+	}
+
+	return rows
+}
+
+// parseDisabledHost tries to parse a commented-out line as a disabled
+// Host row. It returns false if the commented text isn't "# ip hostname
+// [hostname ...]", i.e. it's an ordinary comment.
+func parseDisabledHost(line string) (Host, bool) {
+	body := strings.TrimSpace(strings.TrimPrefix(line, "#"))
+
+	fields := strings.Fields(body)
+	if len(fields) < 2 {
+		return Host{}, false
+	}
+
+	if net.ParseIP(fields[0]) == nil {
+		return Host{}, false
+	}
+
+	return Host{IP: fields[0], Hostnames: fields[1:], Enabled: false}, true
+}
+
+// RenderHosts transforms the code representation of a hosts file to string,
+// rendering Host rows in the given format.
+func RenderHosts(rows []interface{}, format Format) string {
+	var buf bytes.Buffer
+
+	for _, row := range rows {
+		switch v := row.(type) {
+
+		case string:
+			buf.WriteString(v)
+			buf.WriteString("\n")
+
+		case Host:
+			writeHostRow(&buf, v, format)
+		}
+	}
+
+	return buf.String()
+}
+
+// RenderHostsWithoutComments transforms the code representation of a hosts
+// file to string, but ignores empty and commented (that starts with #) rows.
+func RenderHostsWithoutComments(rows []interface{}, format Format) string {
+	var buf bytes.Buffer
+
+	for _, row := range rows {
+		if v, ok := row.(Host); ok {
+			writeHostRow(&buf, v, format)
+		}
+	}
+
+	return buf.String()
+}
+
+// writeHostRow appends host to buf. FormatUnix groups every hostname onto
+// one line; FormatWindows writes one "IP\thostname" pair per line. A
+// disabled host is written back out commented, so it round-trips through
+// Load/Save instead of silently re-enabling.
+func writeHostRow(buf *bytes.Buffer, host Host, format Format) {
+	prefix := ""
+	if !host.Enabled {
+		prefix = "# "
+	}
+
+	if format == FormatWindows {
+		for _, hostname := range host.Hostnames {
+			buf.WriteString(prefix + host.IP + "\t" + hostname + "\n")
+		}
+		return
+	}
+
+	hostnames := strings.Join(host.Hostnames, " ")
+	buf.WriteString(prefix + host.IP + "\t" + hostnames + "\n")
+}
+
+// SetFormat selects how Host rows are rendered by Render and Save.
+func (h *Hostfile) SetFormat(format Format) {
+	h.mu.Lock()
+	h.format = format
+	h.mu.Unlock()
+}
+
+// Render returns the full contents of the hosts file, including comments
+// and blank lines, as it would be written to disk by Save.
+func (h *Hostfile) Render() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return RenderHosts(h.rows, h.format)
+}
+
+// Hosts returns every Host row currently loaded, skipping comments and
+// blank lines.
+func (h *Hostfile) Hosts() []Host {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var out []Host
+	for _, row := range h.rows {
+		if host, ok := row.(Host); ok {
+			out = append(out, host)
+		}
+	}
+
+	return out
+}
+
+// AddHost adds hostnames to the row for ip, merging with any existing row
+// for that exact IP and de-duplicating hostnames. IPv4 and IPv6 addresses
+// are distinct keys, so AddHost("::1", "foo") and AddHost("127.0.0.1",
+// "foo") coexist as separate rows rather than colliding. It returns an
+// error if ip isn't a valid IPv4 or IPv6 address.
+func (h *Hostfile) AddHost(ip string, hostnames ...string) error {
+	if net.ParseIP(ip) == nil {
+		return fmt.Errorf("hosts: invalid IP address %q", ip)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, row := range h.rows {
+		if host, ok := row.(Host); ok && host.IP == ip {
+			host.Hostnames = uniqueStrings(append(host.Hostnames, hostnames...))
+			h.rows[i] = host
+			return nil
+		}
+	}
+
+	h.rows = append(h.rows, Host{IP: ip, Hostnames: uniqueStrings(hostnames), Enabled: true})
+
+	return nil
+}
+
+// SetEnabled enables or disables every row whose Hostnames include
+// hostname, returning whether any row matched. Disabling a hostname that
+// shares a row with other hostnames splits it into its own row so the
+// other hostnames are unaffected.
+func (h *Hostfile) SetEnabled(hostname string, enabled bool) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	found := false
+
+	for i := 0; i < len(h.rows); i++ {
+		host, ok := h.rows[i].(Host)
+		if !ok {
+			continue
+		}
+
+		if indexOf(host.Hostnames, hostname) == -1 {
+			continue
+		}
+
+		found = true
+
+		if len(host.Hostnames) == 1 {
+			host.Enabled = enabled
+			h.rows[i] = host
+			continue
+		}
+
+		host.Hostnames = removeHostname(host.Hostnames, hostname)
+		h.rows[i] = host
+		h.rows = append(h.rows, Host{IP: host.IP, Hostnames: []string{hostname}, Enabled: enabled})
+	}
+
+	return found
+}
+
+// RemoveHost removes the given hostnames from every row, dropping rows
+// that end up with no hostnames left.
+func (h *Hostfile) RemoveHost(hostnames ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i := len(h.rows) - 1; i >= 0; i-- {
+		host, ok := h.rows[i].(Host)
+		if !ok {
+			continue
+		}
+
+		for _, hostname := range hostnames {
+			host.Hostnames = removeHostname(host.Hostnames, hostname)
+		}
+
+		if len(host.Hostnames) > 0 {
+			h.rows[i] = host
+		} else {
+			h.rows = append(h.rows[:i], h.rows[i+1:]...)
+		}
+	}
+}
+
+// RemoveIP removes every row for the given IP address.
+func (h *Hostfile) RemoveIP(ip string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i := len(h.rows) - 1; i >= 0; i-- {
+		if host, ok := h.rows[i].(Host); ok && host.IP == ip {
+			h.rows = append(h.rows[:i], h.rows[i+1:]...)
+		}
+	}
+}
+
+// ListHostsByIP returns the hostnames associated with the given IP,
+// skipping rows disabled via `hosts off`.
+func (h *Hostfile) ListHostsByIP(ip string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, row := range h.rows {
+		if host, ok := row.(Host); ok && host.IP == ip && host.Enabled {
+			return host.Hostnames
+		}
+	}
+
+	return nil
+}
+
+// ListAddressesByHost returns the [ip, hostname] pairs matching hostname,
+// skipping rows disabled via `hosts off`. When exact is false, hostname
+// is also matched as a domain suffix, e.g. "example.com" matches
+// "www.example.com".
+func (h *Hostfile) ListAddressesByHost(hostname string, exact bool) [][]string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var matches [][]string
+
+	for _, row := range h.rows {
+		host, ok := row.(Host)
+		if !ok || !host.Enabled {
+			continue
+		}
+
+		for _, name := range host.Hostnames {
+			if name == hostname || (!exact && strings.HasSuffix(name, "."+hostname)) {
+				matches = append(matches, []string{host.IP, name})
+			}
+		}
+	}
+
+	return matches
+}
+
+// ListHostsByCIDR returns the [ip, hostname] pairs whose IP falls within
+// the given CIDR block, skipping rows disabled via `hosts off`.
+func (h *Hostfile) ListHostsByCIDR(cidr string) ([][]string, error) {
+	_, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var matches [][]string
+
+	for _, row := range h.rows {
+		host, ok := row.(Host)
+		if !ok || !host.Enabled {
+			continue
+		}
+
+		ip := net.ParseIP(host.IP)
+		if ip == nil || !subnet.Contains(ip) {
+			continue
+		}
+
+		for _, name := range host.Hostnames {
+			matches = append(matches, []string{host.IP, name})
+		}
+	}
+
+	return matches, nil
+}
+
+func indexOf(s []string, v string) int {
+	for i, x := range s {
+		if x == v {
+			return i
+		}
+	}
+	return -1
+}
+
+func uniqueStrings(s []string) []string {
+	seen := make(map[string]struct{}, len(s))
+	j := 0
+	for _, v := range s {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		s[j] = v
+		j++
+	}
+	return s[:j]
+}
+
+func removeHostname(hostnames []string, remove string) []string {
+	removeIndex := -1
+	for i, hostname := range hostnames {
+		if hostname == remove {
+			removeIndex = i
+		}
+	}
+	if removeIndex != -1 {
+		hostnames = append(hostnames[:removeIndex], hostnames[removeIndex+1:]...)
+	}
+	return hostnames
+}
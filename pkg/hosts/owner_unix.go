@@ -0,0 +1,20 @@
+//go:build !windows
+
+package hosts
+
+import (
+	"os"
+	"syscall"
+)
+
+// preserveOwner applies the uid/gid of ref to path, best-effort. It is a
+// no-op if the underlying stat information isn't a *syscall.Stat_t or the
+// chown fails (e.g. when not running as root).
+func preserveOwner(path string, ref os.FileInfo) {
+	stat, ok := ref.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+
+	_ = os.Chown(path, int(stat.Uid), int(stat.Gid))
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package hosts
+
+import "os"
+
+// preserveOwner is a no-op on Windows, which has no POSIX uid/gid to copy.
+func preserveOwner(path string, ref os.FileInfo) {}
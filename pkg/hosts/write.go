@@ -0,0 +1,93 @@
+package hosts
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Save writes the current contents back to the hosts file path. The write
+// is atomic: the new contents are written and fsynced to a temp file in
+// the same directory, then renamed over the target, so a process killed
+// mid-write can never leave a truncated hosts file behind. If backup is
+// true, the previous contents are copied to "<path>.bak" first.
+func (h *Hostfile) Save(backup bool) error {
+	h.mu.RLock()
+	rendered := RenderHosts(h.rows, h.format)
+	h.mu.RUnlock()
+
+	if backup {
+		if err := copyFile(h.path, h.path+".bak"); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return writeFileAtomic(h.path, []byte(rendered))
+}
+
+// Restore replaces the hosts file with the contents of its "<path>.bak"
+// backup, created by a previous Save(true).
+func (h *Hostfile) Restore() error {
+	backupPath := h.path + ".bak"
+
+	data, err := ioutil.ReadFile(backupPath)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(h.path, data)
+}
+
+// writeFileAtomic writes data to path by writing it to a temp file in the
+// same directory, fsyncing it, then renaming it over path. The temp file
+// inherits path's existing mode and ownership, falling back to 0644 and
+// the current process owner for a path that doesn't yet exist.
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	info, err := os.Stat(path)
+	if err == nil {
+		mode = info.Mode()
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".hosts-tmp-")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once the rename below succeeds
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return err
+	}
+	if info != nil {
+		preserveOwner(tmpPath, info)
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// copyFile copies src to dst, preserving src's mode.
+func copyFile(src, dst string) error {
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(src); err == nil {
+		mode = info.Mode()
+	}
+
+	return ioutil.WriteFile(dst, data, mode)
+}
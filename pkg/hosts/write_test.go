@@ -0,0 +1,42 @@
+package hosts
+
+import "testing"
+
+func TestSaveBackupAndRestore(t *testing.T) {
+	hf := writeTempHostfile(t, "10.0.0.1 myapp\n")
+
+	if err := hf.Save(true); err != nil {
+		t.Fatalf("Save(true): %v", err)
+	}
+
+	if err := hf.AddHost("10.0.0.2", "other"); err != nil {
+		t.Fatalf("AddHost: %v", err)
+	}
+	if err := hf.Save(true); err != nil {
+		t.Fatalf("Save(true) after AddHost: %v", err)
+	}
+
+	if err := hf.Restore(); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	restored := NewHostfile(hf.path)
+	if err := restored.Load(); err != nil {
+		t.Fatalf("Load after Restore: %v", err)
+	}
+
+	if got := restored.ListHostsByIP("10.0.0.2"); got != nil {
+		t.Fatalf("ListHostsByIP(10.0.0.2) after Restore = %v, want nil (restored from first backup)", got)
+	}
+	if got := restored.ListHostsByIP("10.0.0.1"); len(got) != 1 || got[0] != "myapp" {
+		t.Fatalf("ListHostsByIP(10.0.0.1) after Restore = %v, want [myapp]", got)
+	}
+}
+
+func TestRestoreWithoutBackupFails(t *testing.T) {
+	hf := writeTempHostfile(t, "10.0.0.1 myapp\n")
+
+	if err := hf.Restore(); err == nil {
+		t.Fatal("Restore with no prior backup: want error, got nil")
+	}
+}